@@ -0,0 +1,14 @@
+package main
+
+import (
+	"os"
+
+	"github.com/stellar/go/support/log"
+)
+
+func main() {
+	if err := rootCmd().Execute(); err != nil {
+		log.Errorf("running wallet-backend: %v", err)
+		os.Exit(1)
+	}
+}
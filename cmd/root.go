@@ -0,0 +1,17 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var databaseURL string
+
+func rootCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "wallet-backend",
+		Short: "Stellar wallet-backend service and administration CLI",
+	}
+	cmd.PersistentFlags().StringVar(&databaseURL, "database-url", "", "Postgres connection string")
+	cmd.AddCommand(usersCmd())
+	return cmd
+}
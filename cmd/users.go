@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/stellar/wallet-backend/internal/auth"
+	"github.com/stellar/wallet-backend/internal/db"
+)
+
+// usersCmd groups the `wallet-backend users` subcommands.
+func usersCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "users",
+		Short: "Manage API users",
+	}
+	cmd.AddCommand(usersAddCmd())
+	return cmd
+}
+
+func usersAddCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "add <email>",
+		Short: "Create a new API user and print its bearer token",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			email := args[0]
+
+			dbConnectionPool, err := db.OpenDBConnectionPool(databaseURL)
+			if err != nil {
+				return fmt.Errorf("opening database connection pool: %w", err)
+			}
+			defer dbConnectionPool.Close()
+
+			token, err := auth.NewUsersModel(dbConnectionPool).Create(context.Background(), email)
+			if err != nil {
+				return fmt.Errorf("creating user %q: %w", email, err)
+			}
+
+			fmt.Printf("User %q created. API token (store it now, it cannot be retrieved again):\n%s\n", email, token)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/stellar/wallet-backend/internal/serve/httperror"
+)
+
+// AdminMiddleware returns an http middleware that requires a valid
+// `Authorization: Bearer <token>` header matching adminToken. It's meant for
+// operator-only endpoints (e.g. webhook delivery administration) that aren't
+// scoped to a single API user and so can't use Middleware. If adminToken is
+// empty, every request is rejected: there is no way to accidentally run
+// these endpoints open.
+func AdminMiddleware(adminToken string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok || adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+				httperror.NewUnauthorizedError("Missing or invalid admin credentials").Render(w)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/stellar/wallet-backend/internal/serve/httperror"
+)
+
+type contextKey string
+
+const userIDContextKey contextKey = "userID"
+
+// Authenticator looks up the user behind an API token. It is satisfied by
+// *UsersModel.
+type Authenticator interface {
+	GetByToken(ctx context.Context, token string) (*User, error)
+}
+
+// Middleware returns an http middleware that requires a valid
+// `Authorization: Bearer <token>` header and injects the resolved user id
+// into the request context.
+func Middleware(authenticator Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				httperror.NewUnauthorizedError("Missing or malformed Authorization header").Render(w)
+				return
+			}
+
+			user, err := authenticator.GetByToken(r.Context(), token)
+			if errors.Is(err, ErrUserNotFound) {
+				httperror.NewUnauthorizedError("Invalid or revoked API token").Render(w)
+				return
+			} else if err != nil {
+				httperror.NewInternalServerError().Render(w)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userIDContextKey, user.ID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// UserIDFromContext returns the authenticated caller's user id, as injected
+// by Middleware.
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userIDContextKey).(string)
+	return userID, ok
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if !strings.HasPrefix(header, prefix) {
+		return "", false
+	}
+
+	token := strings.TrimSpace(strings.TrimPrefix(header, prefix))
+	if token == "" {
+		return "", false
+	}
+
+	return token, true
+}
@@ -0,0 +1,80 @@
+// Package auth manages API users and the bearer tokens used to authenticate
+// requests to the mutating /payments endpoints.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/stellar/wallet-backend/internal/db"
+)
+
+// ErrUserNotFound is returned when no user matches the given token.
+var ErrUserNotFound = errors.New("user not found")
+
+// User is an API consumer authenticating with a bearer token.
+type User struct {
+	ID        string `db:"id"`
+	Email     string `db:"email"`
+	TokenHash string `db:"api_token_hash"`
+}
+
+// UsersModel manages persistence of API users.
+type UsersModel struct {
+	dbConnectionPool db.DBConnectionPool
+}
+
+// NewUsersModel builds a UsersModel backed by the given connection pool.
+func NewUsersModel(dbConnectionPool db.DBConnectionPool) *UsersModel {
+	return &UsersModel{dbConnectionPool: dbConnectionPool}
+}
+
+// Create inserts a new user with email and returns the plaintext API token.
+// Only the token's hash is persisted; the plaintext value is shown once and
+// cannot be recovered.
+func (m *UsersModel) Create(ctx context.Context, email string) (token string, err error) {
+	token, err = generateToken()
+	if err != nil {
+		return "", fmt.Errorf("generating API token: %w", err)
+	}
+
+	const query = `INSERT INTO users (email, api_token_hash) VALUES ($1, $2)`
+	if _, err := m.dbConnectionPool.ExecContext(ctx, query, email, hashToken(token)); err != nil {
+		return "", fmt.Errorf("inserting user %q: %w", email, err)
+	}
+
+	return token, nil
+}
+
+// GetByToken looks up the user whose API token matches token.
+func (m *UsersModel) GetByToken(ctx context.Context, token string) (*User, error) {
+	user := &User{}
+	const query = `SELECT id, email, api_token_hash FROM users WHERE api_token_hash = $1 AND revoked_at IS NULL`
+	err := m.dbConnectionPool.GetContext(ctx, user, query, hashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("fetching user by token: %w", err)
+	}
+
+	return user, nil
+}
+
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
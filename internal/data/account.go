@@ -0,0 +1,84 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/stellar/wallet-backend/internal/db"
+)
+
+// Account is a Stellar address subscribed by a user for payment indexing.
+type Account struct {
+	StellarAddress string    `db:"stellar_address"`
+	UserID         string    `db:"user_id"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+// AccountModel manages persistence of subscribed Stellar accounts.
+type AccountModel struct {
+	dbConnectionPool db.SQLExecer
+}
+
+// NewAccountModel builds an AccountModel backed by querier, which may be a
+// *db.DBConnectionPool or a *sqlx.Tx, so callers can run account operations
+// as part of a larger transaction.
+func NewAccountModel(querier db.SQLExecer) *AccountModel {
+	return &AccountModel{dbConnectionPool: querier}
+}
+
+// InsertIfNotExists subscribes address on behalf of userID. inserted is false
+// if the address was already subscribed by any user.
+func (m *AccountModel) InsertIfNotExists(ctx context.Context, userID, address string) (inserted bool, err error) {
+	const query = `
+		INSERT INTO accounts (stellar_address, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (stellar_address) DO NOTHING
+	`
+	result, err := m.dbConnectionPool.ExecContext(ctx, query, address, userID)
+	if err != nil {
+		return false, fmt.Errorf("inserting account %q: %w", address, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("reading rows affected for account %q: %w", address, err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// Delete removes address from the accounts table, scoped to userID so that
+// callers can only unsubscribe addresses they own. deleted is false if the
+// address wasn't subscribed by that user.
+func (m *AccountModel) Delete(ctx context.Context, userID, address string) (deleted bool, err error) {
+	const query = `DELETE FROM accounts WHERE stellar_address = $1 AND user_id = $2`
+	result, err := m.dbConnectionPool.ExecContext(ctx, query, address, userID)
+	if err != nil {
+		return false, fmt.Errorf("deleting account %q: %w", address, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("reading rows affected for account %q: %w", address, err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// ListByUser returns the addresses subscribed by userID, ordered by
+// subscription time, paginated with limit/offset.
+func (m *AccountModel) ListByUser(ctx context.Context, userID string, limit, offset int) ([]Account, error) {
+	var accounts []Account
+	const query = `
+		SELECT stellar_address, user_id, created_at
+		FROM accounts
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+		LIMIT $2 OFFSET $3
+	`
+	if err := m.dbConnectionPool.SelectContext(ctx, &accounts, query, userID, limit, offset); err != nil {
+		return nil, fmt.Errorf("listing accounts for user %q: %w", userID, err)
+	}
+	return accounts, nil
+}
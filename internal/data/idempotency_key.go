@@ -0,0 +1,85 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stellar/wallet-backend/internal/db"
+)
+
+// IdempotencyKeyTTL is how long a stored idempotency key is honored before
+// a replay is treated as a brand new request.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// ErrIdempotencyKeyNotFound is returned when no (non-expired) record matches
+// the given key.
+var ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+
+// IdempotencyKey records the response previously returned for a given user's
+// idempotency key, so replays can return the exact same response. Keys are
+// scoped per user (Stripe-style) so two users can independently reuse the
+// same key string without interfering with each other.
+type IdempotencyKey struct {
+	UserID         string          `db:"user_id"`
+	Key            string          `db:"key"`
+	RequestHash    string          `db:"request_hash"`
+	ResponseStatus int             `db:"response_status"`
+	ResponseBody   json.RawMessage `db:"response_body"`
+	CreatedAt      time.Time       `db:"created_at"`
+}
+
+// IdempotencyKeyModel manages persistence of idempotency keys.
+type IdempotencyKeyModel struct {
+	dbConnectionPool db.SQLExecer
+}
+
+// NewIdempotencyKeyModel builds an IdempotencyKeyModel backed by querier,
+// which may be a *db.DBConnectionPool or a *sqlx.Tx.
+func NewIdempotencyKeyModel(querier db.SQLExecer) *IdempotencyKeyModel {
+	return &IdempotencyKeyModel{dbConnectionPool: querier}
+}
+
+// Get returns userID's record for key, provided it hasn't expired.
+func (m *IdempotencyKeyModel) Get(ctx context.Context, userID, key string) (*IdempotencyKey, error) {
+	record := &IdempotencyKey{}
+	const query = `
+		SELECT user_id, key, request_hash, response_status, response_body, created_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND key = $2 AND created_at > $3
+	`
+	err := m.dbConnectionPool.GetContext(ctx, record, query, userID, key, time.Now().Add(-IdempotencyKeyTTL))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrIdempotencyKeyNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("fetching idempotency key %q for user %q: %w", key, userID, err)
+	}
+	return record, nil
+}
+
+// Insert stores the response returned for userID's key so that replays can be
+// answered from cache. inserted is false if another request already won the
+// race and inserted a record for (userID, key) first — the caller's own
+// mutations must then be rolled back, since only the winner's response is
+// the one replays will see.
+func (m *IdempotencyKeyModel) Insert(ctx context.Context, userID, key, requestHash string, responseStatus int, responseBody []byte) (inserted bool, err error) {
+	const query = `
+		INSERT INTO idempotency_keys (user_id, key, request_hash, response_status, response_body)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, key) DO NOTHING
+	`
+	result, err := m.dbConnectionPool.ExecContext(ctx, query, userID, key, requestHash, responseStatus, responseBody)
+	if err != nil {
+		return false, fmt.Errorf("inserting idempotency key %q for user %q: %w", key, userID, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("reading rows affected for idempotency key %q for user %q: %w", key, userID, err)
+	}
+
+	return rowsAffected > 0, nil
+}
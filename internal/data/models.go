@@ -0,0 +1,39 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stellar/wallet-backend/internal/db"
+)
+
+// Models aggregates all data models so handlers only need to depend on a
+// single struct to reach the database.
+type Models struct {
+	Account               *AccountModel
+	SubscriptionChallenge *SubscriptionChallengeModel
+	SubscriptionWebhook   *SubscriptionWebhookModel
+	WebhookDelivery       *WebhookDeliveryModel
+	IdempotencyKey        *IdempotencyKeyModel
+
+	dbConnectionPool db.DBConnectionPool
+}
+
+// NewModels builds a Models instance backed by the given connection pool.
+func NewModels(dbConnectionPool db.DBConnectionPool) (*Models, error) {
+	return &Models{
+		Account:               NewAccountModel(dbConnectionPool),
+		SubscriptionChallenge: &SubscriptionChallengeModel{dbConnectionPool: dbConnectionPool},
+		SubscriptionWebhook:   &SubscriptionWebhookModel{dbConnectionPool: dbConnectionPool},
+		WebhookDelivery:       &WebhookDeliveryModel{dbConnectionPool: dbConnectionPool},
+		IdempotencyKey:        &IdempotencyKeyModel{dbConnectionPool: dbConnectionPool},
+		dbConnectionPool:      dbConnectionPool,
+	}, nil
+}
+
+// BeginTxx starts a new transaction, for callers that need to run several
+// model operations atomically (e.g. batch subscribe/unsubscribe).
+func (m *Models) BeginTxx(ctx context.Context) (*sqlx.Tx, error) {
+	return m.dbConnectionPool.BeginTxx(ctx, &sql.TxOptions{})
+}
@@ -0,0 +1,151 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stellar/wallet-backend/internal/db"
+)
+
+// ChallengeTTL is how long a subscription challenge stays valid before it
+// must be rejected as expired.
+const ChallengeTTL = 5 * time.Minute
+
+// ErrChallengeNotFound is returned when no challenge matches the given id.
+var ErrChallengeNotFound = errors.New("subscription challenge not found")
+
+// ErrChallengeAlreadyUsed is returned when a challenge has already been
+// consumed by a previous subscribe attempt.
+var ErrChallengeAlreadyUsed = errors.New("subscription challenge already used")
+
+// ErrChallengeExpired is returned when a challenge's expiry has passed.
+var ErrChallengeExpired = errors.New("subscription challenge expired")
+
+// SubscriptionChallenge is a one-time nonce issued to prove ownership of a
+// Stellar address before it can be subscribed.
+type SubscriptionChallenge struct {
+	ID             string       `db:"id"`
+	StellarAddress string       `db:"stellar_address"`
+	Nonce          string       `db:"nonce"`
+	ExpiresAt      time.Time    `db:"expires_at"`
+	UsedAt         sql.NullTime `db:"used_at"`
+	CreatedAt      time.Time    `db:"created_at"`
+}
+
+// SubscriptionChallengeModel manages persistence of subscription challenges.
+type SubscriptionChallengeModel struct {
+	dbConnectionPool db.DBConnectionPool
+}
+
+// Insert generates a random nonce for address and persists it with a
+// ChallengeTTL expiry, returning the created challenge.
+func (m *SubscriptionChallengeModel) Insert(ctx context.Context, address string) (*SubscriptionChallenge, error) {
+	nonce, err := generateNonce()
+	if err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+
+	challenge := &SubscriptionChallenge{}
+	const query = `
+		INSERT INTO subscription_challenges (stellar_address, nonce, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, stellar_address, nonce, expires_at, used_at, created_at
+	`
+	expiresAt := time.Now().Add(ChallengeTTL)
+	if err := m.dbConnectionPool.GetContext(ctx, challenge, query, address, nonce, expiresAt); err != nil {
+		return nil, fmt.Errorf("inserting subscription challenge for %q: %w", address, err)
+	}
+
+	return challenge, nil
+}
+
+// Get returns the challenge identified by (id, address) without marking it
+// used, provided it exists, isn't expired, and hasn't already been used.
+// Callers that need to verify a signature against the nonce before
+// committing to consuming the challenge should call Get first and only call
+// Consume once the signature checks out, so a bad signature attempt never
+// burns a nonce the legitimate holder hasn't used yet.
+func (m *SubscriptionChallengeModel) Get(ctx context.Context, id, address string) (*SubscriptionChallenge, error) {
+	challenge := &SubscriptionChallenge{}
+	const query = `
+		SELECT id, stellar_address, nonce, expires_at, used_at, created_at
+		FROM subscription_challenges
+		WHERE id = $1 AND stellar_address = $2
+	`
+	err := m.dbConnectionPool.GetContext(ctx, challenge, query, id, address)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrChallengeNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("fetching subscription challenge %q: %w", id, err)
+	}
+
+	if challenge.UsedAt.Valid {
+		return nil, ErrChallengeAlreadyUsed
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		return nil, ErrChallengeExpired
+	}
+
+	return challenge, nil
+}
+
+// Consume atomically marks the challenge identified by (id, address) as used
+// and returns it, provided it exists, isn't expired, and hasn't already been
+// used. The check and the mark happen in a single conditioned UPDATE so that
+// two concurrent calls for the same challenge can't both succeed. Callers
+// that need to verify a signature first should call Get, then Consume only
+// after the signature is confirmed valid — see Get's doc comment.
+func (m *SubscriptionChallengeModel) Consume(ctx context.Context, id, address string) (*SubscriptionChallenge, error) {
+	challenge := &SubscriptionChallenge{}
+	const updateQuery = `
+		UPDATE subscription_challenges
+		SET used_at = NOW()
+		WHERE id = $1 AND stellar_address = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, stellar_address, nonce, expires_at, used_at, created_at
+	`
+	err := m.dbConnectionPool.GetContext(ctx, challenge, updateQuery, id, address)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, m.classifyConsumeFailure(ctx, id, address)
+	} else if err != nil {
+		return nil, fmt.Errorf("consuming subscription challenge %q: %w", id, err)
+	}
+
+	return challenge, nil
+}
+
+// classifyConsumeFailure is called after Consume's UPDATE affects no rows, to
+// turn that into a specific error for the caller. It's purely diagnostic: the
+// UPDATE itself is what guarantees a challenge can't be consumed twice, this
+// just explains why this particular attempt didn't match.
+func (m *SubscriptionChallengeModel) classifyConsumeFailure(ctx context.Context, id, address string) error {
+	existing := &SubscriptionChallenge{}
+	const selectQuery = `
+		SELECT id, stellar_address, nonce, expires_at, used_at, created_at
+		FROM subscription_challenges
+		WHERE id = $1 AND stellar_address = $2
+	`
+	err := m.dbConnectionPool.GetContext(ctx, existing, selectQuery, id, address)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrChallengeNotFound
+	} else if err != nil {
+		return fmt.Errorf("fetching subscription challenge %q: %w", id, err)
+	}
+
+	if existing.UsedAt.Valid {
+		return ErrChallengeAlreadyUsed
+	}
+	return ErrChallengeExpired
+}
+
+func generateNonce() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
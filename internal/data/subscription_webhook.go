@@ -0,0 +1,76 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stellar/wallet-backend/internal/db"
+)
+
+// ErrSubscriptionWebhookNotFound is returned when no webhook matches the
+// given id.
+var ErrSubscriptionWebhookNotFound = errors.New("subscription webhook not found")
+
+// SubscriptionWebhook is a callback registered to be notified of payments on
+// a subscribed Stellar address.
+type SubscriptionWebhook struct {
+	ID             string    `db:"id"`
+	StellarAddress string    `db:"stellar_address"`
+	CallbackURL    string    `db:"callback_url"`
+	Secret         string    `db:"secret"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+// SubscriptionWebhookModel manages persistence of subscription webhooks.
+type SubscriptionWebhookModel struct {
+	dbConnectionPool db.DBConnectionPool
+}
+
+// Insert registers callbackURL to be notified of payments to address, signed
+// with secret, and returns the created webhook.
+func (m *SubscriptionWebhookModel) Insert(ctx context.Context, address, callbackURL, secret string) (*SubscriptionWebhook, error) {
+	webhook := &SubscriptionWebhook{}
+	const query = `
+		INSERT INTO subscription_webhooks (stellar_address, callback_url, secret)
+		VALUES ($1, $2, $3)
+		RETURNING id, stellar_address, callback_url, secret, created_at
+	`
+	if err := m.dbConnectionPool.GetContext(ctx, webhook, query, address, callbackURL, secret); err != nil {
+		return nil, fmt.Errorf("inserting subscription webhook for %q: %w", address, err)
+	}
+	return webhook, nil
+}
+
+// GetByAddress returns all webhooks registered for address.
+func (m *SubscriptionWebhookModel) GetByAddress(ctx context.Context, address string) ([]SubscriptionWebhook, error) {
+	var webhooks []SubscriptionWebhook
+	const query = `
+		SELECT id, stellar_address, callback_url, secret, created_at
+		FROM subscription_webhooks
+		WHERE stellar_address = $1
+	`
+	if err := m.dbConnectionPool.SelectContext(ctx, &webhooks, query, address); err != nil {
+		return nil, fmt.Errorf("fetching subscription webhooks for %q: %w", address, err)
+	}
+	return webhooks, nil
+}
+
+// GetByID fetches a single webhook by id.
+func (m *SubscriptionWebhookModel) GetByID(ctx context.Context, id string) (*SubscriptionWebhook, error) {
+	webhook := &SubscriptionWebhook{}
+	const query = `
+		SELECT id, stellar_address, callback_url, secret, created_at
+		FROM subscription_webhooks
+		WHERE id = $1
+	`
+	err := m.dbConnectionPool.GetContext(ctx, webhook, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSubscriptionWebhookNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("fetching subscription webhook %q: %w", id, err)
+	}
+	return webhook, nil
+}
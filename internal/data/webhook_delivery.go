@@ -0,0 +1,165 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stellar/wallet-backend/internal/db"
+)
+
+// Webhook delivery statuses.
+const (
+	WebhookDeliveryStatusPending   = "pending"
+	WebhookDeliveryStatusSucceeded = "succeeded"
+	WebhookDeliveryStatusFailed    = "failed"
+	WebhookDeliveryStatusDead      = "dead"
+)
+
+// ErrWebhookDeliveryNotFound is returned when no delivery matches the given id.
+var ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+
+// WebhookDelivery tracks a single attempt (and its retries) to notify a
+// webhook of a payment event.
+type WebhookDelivery struct {
+	ID            string          `db:"id"`
+	WebhookID     string          `db:"webhook_id"`
+	TxHash        string          `db:"tx_hash"`
+	Payload       json.RawMessage `db:"payload"`
+	Status        string          `db:"status"`
+	Attempts      int             `db:"attempts"`
+	NextAttemptAt time.Time       `db:"next_attempt_at"`
+	LastError     sql.NullString  `db:"last_error"`
+	CreatedAt     time.Time       `db:"created_at"`
+	UpdatedAt     time.Time       `db:"updated_at"`
+}
+
+// WebhookDeliveryModel manages persistence of webhook delivery attempts.
+type WebhookDeliveryModel struct {
+	dbConnectionPool db.DBConnectionPool
+}
+
+// InsertIfNotExists creates a pending delivery for (webhookID, txHash), or
+// returns the existing one if this event was already recorded. This makes
+// enqueuing a delivery idempotent against duplicate payment events.
+func (m *WebhookDeliveryModel) InsertIfNotExists(ctx context.Context, webhookID, txHash string, payload []byte) (*WebhookDelivery, error) {
+	const insertQuery = `
+		INSERT INTO webhook_deliveries (webhook_id, tx_hash, payload)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (webhook_id, tx_hash) DO NOTHING
+	`
+	if _, err := m.dbConnectionPool.ExecContext(ctx, insertQuery, webhookID, txHash, payload); err != nil {
+		return nil, fmt.Errorf("inserting webhook delivery for webhook %q tx %q: %w", webhookID, txHash, err)
+	}
+
+	delivery := &WebhookDelivery{}
+	const selectQuery = `
+		SELECT id, webhook_id, tx_hash, payload, status, attempts, next_attempt_at, last_error, created_at, updated_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1 AND tx_hash = $2
+	`
+	if err := m.dbConnectionPool.GetContext(ctx, delivery, selectQuery, webhookID, txHash); err != nil {
+		return nil, fmt.Errorf("fetching webhook delivery for webhook %q tx %q: %w", webhookID, txHash, err)
+	}
+
+	return delivery, nil
+}
+
+// ListDue returns pending or failed (awaiting retry) deliveries whose
+// next_attempt_at has passed, ordered oldest first, up to limit rows.
+func (m *WebhookDeliveryModel) ListDue(ctx context.Context, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	const query = `
+		SELECT id, webhook_id, tx_hash, payload, status, attempts, next_attempt_at, last_error, created_at, updated_at
+		FROM webhook_deliveries
+		WHERE status IN ($1, $2) AND next_attempt_at <= NOW()
+		ORDER BY next_attempt_at ASC
+		LIMIT $3
+	`
+	if err := m.dbConnectionPool.SelectContext(ctx, &deliveries, query, WebhookDeliveryStatusPending, WebhookDeliveryStatusFailed, limit); err != nil {
+		return nil, fmt.Errorf("listing due webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// ListFailed returns deliveries that are no longer being retried
+// automatically (status "dead"), for the admin UI to inspect/force-retry.
+func (m *WebhookDeliveryModel) ListFailed(ctx context.Context, limit, offset int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	const query = `
+		SELECT id, webhook_id, tx_hash, payload, status, attempts, next_attempt_at, last_error, created_at, updated_at
+		FROM webhook_deliveries
+		WHERE status = $1
+		ORDER BY updated_at DESC
+		LIMIT $2 OFFSET $3
+	`
+	if err := m.dbConnectionPool.SelectContext(ctx, &deliveries, query, WebhookDeliveryStatusDead, limit, offset); err != nil {
+		return nil, fmt.Errorf("listing failed webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// MarkSucceeded records that delivery id was delivered successfully.
+func (m *WebhookDeliveryModel) MarkSucceeded(ctx context.Context, id string) error {
+	const query = `UPDATE webhook_deliveries SET status = $1, updated_at = NOW() WHERE id = $2`
+	if _, err := m.dbConnectionPool.ExecContext(ctx, query, WebhookDeliveryStatusSucceeded, id); err != nil {
+		return fmt.Errorf("marking webhook delivery %q succeeded: %w", id, err)
+	}
+	return nil
+}
+
+// MarkFailed records a failed attempt. If nextAttemptAt is nil, the delivery
+// is marked "dead" and will not be retried automatically again.
+func (m *WebhookDeliveryModel) MarkFailed(ctx context.Context, id string, attempts int, nextAttemptAt *time.Time, deliveryErr error) error {
+	status := WebhookDeliveryStatusFailed
+	var next time.Time
+	if nextAttemptAt == nil {
+		status = WebhookDeliveryStatusDead
+	} else {
+		next = *nextAttemptAt
+	}
+
+	const query = `
+		UPDATE webhook_deliveries
+		SET status = $1, attempts = $2, next_attempt_at = $3, last_error = $4, updated_at = NOW()
+		WHERE id = $5
+	`
+	if _, err := m.dbConnectionPool.ExecContext(ctx, query, status, attempts, next, deliveryErr.Error(), id); err != nil {
+		return fmt.Errorf("marking webhook delivery %q failed: %w", id, err)
+	}
+	return nil
+}
+
+// Get fetches a single delivery by id.
+func (m *WebhookDeliveryModel) Get(ctx context.Context, id string) (*WebhookDelivery, error) {
+	delivery := &WebhookDelivery{}
+	const query = `
+		SELECT id, webhook_id, tx_hash, payload, status, attempts, next_attempt_at, last_error, created_at, updated_at
+		FROM webhook_deliveries
+		WHERE id = $1
+	`
+	err := m.dbConnectionPool.GetContext(ctx, delivery, query, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookDeliveryNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("fetching webhook delivery %q: %w", id, err)
+	}
+	return delivery, nil
+}
+
+// ForceRetry resets a dead or failed delivery to pending with an immediate
+// next_attempt_at, for manual retry via the admin endpoint.
+func (m *WebhookDeliveryModel) ForceRetry(ctx context.Context, id string) error {
+	const query = `
+		UPDATE webhook_deliveries
+		SET status = $1, next_attempt_at = NOW(), updated_at = NOW()
+		WHERE id = $2
+	`
+	if _, err := m.dbConnectionPool.ExecContext(ctx, query, WebhookDeliveryStatusPending, id); err != nil {
+		return fmt.Errorf("forcing retry of webhook delivery %q: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// Package db provides a thin wrapper around sqlx for opening and sharing a
+// single connection pool across the application.
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// SQLExecer is the subset of operations shared by *sqlx.DB and *sqlx.Tx.
+// Models depend on this instead of DBConnectionPool so that the same model
+// code can run against the pool directly or against a transaction.
+type SQLExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// DBConnectionPool is the subset of *sqlx.DB operations used throughout the
+// codebase. It exists so that callers can be tested against a mock without
+// depending on the concrete sqlx type.
+type DBConnectionPool interface {
+	SQLExecer
+	BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error)
+	Close() error
+}
+
+type dbConnectionPool struct {
+	*sqlx.DB
+}
+
+// OpenDBConnectionPool opens a new connection pool to the Postgres database
+// identified by dataSourceName.
+func OpenDBConnectionPool(dataSourceName string) (DBConnectionPool, error) {
+	sqlxDB, err := sqlx.Open("postgres", dataSourceName)
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlx DB: %w", err)
+	}
+
+	if err = sqlxDB.Ping(); err != nil {
+		return nil, fmt.Errorf("pinging database: %w", err)
+	}
+
+	return &dbConnectionPool{sqlxDB}, nil
+}
@@ -0,0 +1,121 @@
+// Package dbtest spins up a disposable Postgres database for use in tests.
+package dbtest
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stellar/wallet-backend/internal/db/migrations"
+)
+
+// rootDatabaseURLEnv names the env var pointing at a Postgres server with
+// permission to CREATE/DROP DATABASE. It must NOT include a database name
+// that tests should write to directly; Open creates a fresh database per
+// test under this server instead.
+const rootDatabaseURLEnv = "TEST_DATABASE_URL"
+
+const defaultRootDatabaseURL = "postgres://postgres@localhost:5432/postgres?sslmode=disable"
+
+// DB represents a disposable test database along with its connection string.
+type DB struct {
+	DSN string
+
+	close func()
+}
+
+// Close tears down the test database.
+func (db *DB) Close() {
+	if db.close != nil {
+		db.close()
+	}
+}
+
+// Open creates a new, empty test database with all migrations applied and
+// returns a handle to it. The database is dropped when Close is called.
+func Open(t *testing.T) *DB {
+	t.Helper()
+
+	dsn, dropFn, err := createTestDatabase()
+	if err != nil {
+		t.Fatalf("creating test database: %v", err)
+	}
+
+	if err := migrations.Up(dsn); err != nil {
+		dropFn()
+		t.Fatalf("running migrations on test database: %v", err)
+	}
+
+	return &DB{
+		DSN:   dsn,
+		close: dropFn,
+	}
+}
+
+func rootDatabaseURL() string {
+	if v := os.Getenv(rootDatabaseURLEnv); v != "" {
+		return v
+	}
+	return defaultRootDatabaseURL
+}
+
+// createTestDatabase connects to the server identified by TEST_DATABASE_URL
+// (or defaultRootDatabaseURL) and creates a randomly-named, empty database
+// for a single test to use. dropFn drops it again.
+func createTestDatabase() (dsn string, dropFn func(), err error) {
+	rootDSN := rootDatabaseURL()
+
+	rootDB, err := sql.Open("postgres", rootDSN)
+	if err != nil {
+		return "", nil, fmt.Errorf("opening root connection %q: %w", rootDatabaseURLEnv, err)
+	}
+	defer rootDB.Close()
+
+	name, err := randomDatabaseName()
+	if err != nil {
+		return "", nil, fmt.Errorf("generating test database name: %w", err)
+	}
+
+	if _, err := rootDB.Exec(fmt.Sprintf("CREATE DATABASE %s", pq.QuoteIdentifier(name))); err != nil {
+		return "", nil, fmt.Errorf("creating test database %q: %w", name, err)
+	}
+
+	dsn, err = withDatabaseName(rootDSN, name)
+	if err != nil {
+		_, _ = rootDB.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %s", pq.QuoteIdentifier(name)))
+		return "", nil, fmt.Errorf("building test database DSN: %w", err)
+	}
+
+	dropFn = func() {
+		rootDB, err := sql.Open("postgres", rootDSN)
+		if err != nil {
+			return
+		}
+		defer rootDB.Close()
+		_, _ = rootDB.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %s WITH (FORCE)", pq.QuoteIdentifier(name)))
+	}
+
+	return dsn, dropFn, nil
+}
+
+func randomDatabaseName() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "wallet_backend_test_" + hex.EncodeToString(buf), nil
+}
+
+func withDatabaseName(rawDSN, name string) (string, error) {
+	u, err := url.Parse(rawDSN)
+	if err != nil {
+		return "", err
+	}
+	u.Path = "/" + name
+	return u.String(), nil
+}
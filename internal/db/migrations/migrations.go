@@ -0,0 +1,34 @@
+// Package migrations embeds the SQL migration files for the wallet-backend
+// schema and exposes a helper for applying them.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// Up applies all pending migrations to the database identified by dsn.
+func Up(dsn string) error {
+	source, err := iofs.New(FS, ".")
+	if err != nil {
+		return fmt.Errorf("loading migration source: %w", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", source, dsn)
+	if err != nil {
+		return fmt.Errorf("creating migrate instance: %w", err)
+	}
+
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		return fmt.Errorf("applying migrations: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+// Package httperror provides a small set of HTTP error helpers that render a
+// consistent `{"error": "...", "extras": {...}}` JSON body.
+package httperror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// HTTPError is an error that knows how to render itself as an HTTP response.
+type HTTPError struct {
+	StatusCode int               `json:"-"`
+	Message    string            `json:"error"`
+	Extras     map[string]string `json:"extras,omitempty"`
+}
+
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+// Render writes the error as a JSON response to w.
+func (e *HTTPError) Render(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.StatusCode)
+	_ = json.NewEncoder(w).Encode(e)
+}
+
+// NewValidationError builds the standard 400 "Validation error." response
+// with per-field details in extras.
+func NewValidationError(extras map[string]string) *HTTPError {
+	return &HTTPError{
+		StatusCode: http.StatusBadRequest,
+		Message:    "Validation error.",
+		Extras:     extras,
+	}
+}
+
+// NewUnauthorizedError builds a 401 response with the given message.
+func NewUnauthorizedError(message string) *HTTPError {
+	return &HTTPError{
+		StatusCode: http.StatusUnauthorized,
+		Message:    message,
+	}
+}
+
+// NewConflictError builds a 409 response with the given message.
+func NewConflictError(message string) *HTTPError {
+	return &HTTPError{
+		StatusCode: http.StatusConflict,
+		Message:    message,
+	}
+}
+
+// NewInternalServerError builds the standard 500 response.
+func NewInternalServerError() *HTTPError {
+	return &HTTPError{
+		StatusCode: http.StatusInternalServerError,
+		Message:    "An internal error occurred while processing this request.",
+	}
+}
@@ -0,0 +1,208 @@
+package httphandler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/wallet-backend/internal/auth"
+	"github.com/stellar/wallet-backend/internal/data"
+	"github.com/stellar/wallet-backend/internal/serve/httperror"
+)
+
+// maxBatchAddresses is the most addresses a single batch subscribe/
+// unsubscribe call may contain.
+const maxBatchAddresses = 500
+
+// Per-address outcomes reported by the batch endpoints.
+const (
+	batchResultOK                = "ok"
+	batchResultAlreadySubscribed = "already_subscribed"
+	batchResultNotSubscribed     = "not_subscribed"
+	batchResultInvalid           = "invalid"
+)
+
+type batchRequest struct {
+	Addresses      []string `json:"addresses"`
+	IdempotencyKey string   `json:"idempotency_key"`
+}
+
+type batchAddressResult struct {
+	Address string `json:"address"`
+	Result  string `json:"result"`
+}
+
+type batchResponse struct {
+	Results []batchAddressResult `json:"results"`
+}
+
+// SubscribeBatch subscribes up to maxBatchAddresses addresses in a single
+// transaction and returns a per-address result. The call is idempotent:
+// replaying the same idempotency_key with the same request body returns the
+// original response, while reusing it with a different body is rejected.
+func (h *PaymentsHandler) SubscribeBatch(w http.ResponseWriter, r *http.Request) {
+	h.batch(w, r, func(ctx context.Context, accountModel *data.AccountModel, userID, address string) (string, error) {
+		inserted, err := accountModel.InsertIfNotExists(ctx, userID, address)
+		if err != nil {
+			return "", err
+		}
+		if inserted {
+			return batchResultOK, nil
+		}
+		return batchResultAlreadySubscribed, nil
+	})
+}
+
+// UnsubscribeBatch unsubscribes up to maxBatchAddresses addresses in a single
+// transaction and returns a per-address result, with the same idempotency_key
+// semantics as SubscribeBatch.
+func (h *PaymentsHandler) UnsubscribeBatch(w http.ResponseWriter, r *http.Request) {
+	h.batch(w, r, func(ctx context.Context, accountModel *data.AccountModel, userID, address string) (string, error) {
+		deleted, err := accountModel.Delete(ctx, userID, address)
+		if err != nil {
+			return "", err
+		}
+		if deleted {
+			return batchResultOK, nil
+		}
+		return batchResultNotSubscribed, nil
+	})
+}
+
+// batchAction applies one address, returning its result tag.
+type batchAction func(ctx context.Context, accountModel *data.AccountModel, userID, address string) (string, error)
+
+func (h *PaymentsHandler) batch(w http.ResponseWriter, r *http.Request, action batchAction) {
+	ctx := r.Context()
+
+	rawBody, err := io.ReadAll(r.Body)
+	if err != nil {
+		httperror.NewValidationError(map[string]string{"body": "Invalid request body"}).Render(w)
+		return
+	}
+
+	var req batchRequest
+	if err := json.Unmarshal(rawBody, &req); err != nil {
+		httperror.NewValidationError(map[string]string{"body": "Invalid request body"}).Render(w)
+		return
+	}
+
+	if req.IdempotencyKey == "" {
+		httperror.NewValidationError(map[string]string{"idempotency_key": "Idempotency key is required"}).Render(w)
+		return
+	}
+	if len(req.Addresses) == 0 || len(req.Addresses) > maxBatchAddresses {
+		httperror.NewValidationError(map[string]string{
+			"addresses": "Must contain between 1 and 500 addresses",
+		}).Render(w)
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(ctx)
+	if !ok {
+		httperror.NewInternalServerError().Render(w)
+		return
+	}
+
+	requestHash := hashRequest(rawBody)
+
+	existing, err := h.Models.IdempotencyKey.Get(ctx, userID, req.IdempotencyKey)
+	if err == nil {
+		if existing.RequestHash != requestHash {
+			httperror.NewConflictError("Idempotency key was already used with a different request").Render(w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(existing.ResponseStatus)
+		_, _ = w.Write(existing.ResponseBody)
+		return
+	} else if !errors.Is(err, data.ErrIdempotencyKeyNotFound) {
+		httperror.NewInternalServerError().Render(w)
+		return
+	}
+
+	tx, err := h.Models.BeginTxx(ctx)
+	if err != nil {
+		httperror.NewInternalServerError().Render(w)
+		return
+	}
+	defer tx.Rollback()
+
+	accountModel := data.NewAccountModel(tx)
+
+	results := make([]batchAddressResult, len(req.Addresses))
+	for i, address := range req.Addresses {
+		if _, err := keypair.ParseAddress(address); err != nil {
+			results[i] = batchAddressResult{Address: address, Result: batchResultInvalid}
+			continue
+		}
+
+		result, err := action(ctx, accountModel, userID, address)
+		if err != nil {
+			httperror.NewInternalServerError().Render(w)
+			return
+		}
+		results[i] = batchAddressResult{Address: address, Result: result}
+	}
+
+	responseBody, err := json.Marshal(batchResponse{Results: results})
+	if err != nil {
+		httperror.NewInternalServerError().Render(w)
+		return
+	}
+
+	inserted, err := data.NewIdempotencyKeyModel(tx).Insert(ctx, userID, req.IdempotencyKey, requestHash, http.StatusOK, responseBody)
+	if err != nil {
+		httperror.NewInternalServerError().Render(w)
+		return
+	}
+	if !inserted {
+		// A concurrent request with the same idempotency key won the race
+		// and committed first: our mutations must not also land, so roll
+		// back and serve whatever the winner recorded instead, or a conflict
+		// if the winner's request body differed from ours.
+		tx.Rollback()
+		h.respondWithExistingIdempotencyResult(w, ctx, userID, req.IdempotencyKey, requestHash)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		httperror.NewInternalServerError().Render(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(responseBody)
+}
+
+// respondWithExistingIdempotencyResult serves the response already recorded
+// for (userID, idempotencyKey), as used when this request lost a concurrent
+// race to record its own result first. If the recorded request hash differs
+// from requestHash, it responds with a conflict instead.
+func (h *PaymentsHandler) respondWithExistingIdempotencyResult(w http.ResponseWriter, ctx context.Context, userID, idempotencyKey, requestHash string) {
+	existing, err := h.Models.IdempotencyKey.Get(ctx, userID, idempotencyKey)
+	if err != nil {
+		httperror.NewInternalServerError().Render(w)
+		return
+	}
+
+	if existing.RequestHash != requestHash {
+		httperror.NewConflictError("Idempotency key was already used with a different request").Render(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(existing.ResponseStatus)
+	_, _ = w.Write(existing.ResponseBody)
+}
+
+func hashRequest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,257 @@
+package httphandler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/wallet-backend/internal/auth"
+	"github.com/stellar/wallet-backend/internal/data"
+	"github.com/stellar/wallet-backend/internal/db"
+	"github.com/stellar/wallet-backend/internal/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupBatchRouter(t *testing.T) (*chi.Mux, db.DBConnectionPool, string) {
+	t.Helper()
+
+	dbt := dbtest.Open(t)
+	t.Cleanup(dbt.Close)
+
+	dbConnectionPool, err := db.OpenDBConnectionPool(dbt.DSN)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = dbConnectionPool.Close() })
+
+	models, err := data.NewModels(dbConnectionPool)
+	require.NoError(t, err)
+	handler := &PaymentsHandler{Models: models}
+
+	r := chi.NewRouter()
+	r.Use(auth.Middleware(auth.NewUsersModel(dbConnectionPool)))
+	r.Post("/payments/subscribe/batch", handler.SubscribeBatch)
+	r.Post("/payments/unsubscribe/batch", handler.UnsubscribeBatch)
+
+	token := mintTestToken(t, dbConnectionPool)
+
+	return r, dbConnectionPool, token
+}
+
+func TestSubscribeBatch(t *testing.T) {
+	r, dbConnectionPool, token := setupBatchRouter(t)
+
+	t.Run("happy_path_mixed_results", func(t *testing.T) {
+		existing := keypair.MustRandom().Address()
+		fresh := keypair.MustRandom().Address()
+		ctx := context.Background()
+		_, err := dbConnectionPool.ExecContext(ctx, "INSERT INTO accounts (stellar_address) VALUES ($1)", existing)
+		require.NoError(t, err)
+
+		payload := fmt.Sprintf(`{"addresses": [%q, %q], "idempotency_key": "key-1"}`, existing, fresh)
+		req := authedRequest(t, http.MethodPost, "/payments/subscribe/batch", payload, token)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp batchResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		require.Len(t, resp.Results, 2)
+		assert.Equal(t, batchResultAlreadySubscribed, resp.Results[0].Result)
+		assert.Equal(t, batchResultOK, resp.Results[1].Result)
+
+		_, _ = dbConnectionPool.ExecContext(context.Background(), "TRUNCATE accounts CASCADE")
+	})
+
+	t.Run("invalid_address_does_not_corrupt_others", func(t *testing.T) {
+		a := keypair.MustRandom().Address()
+		b := keypair.MustRandom().Address()
+
+		payload := fmt.Sprintf(`{"addresses": [%q, "not-an-address", %q], "idempotency_key": "key-2"}`, a, b)
+		req := authedRequest(t, http.MethodPost, "/payments/subscribe/batch", payload, token)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp batchResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		require.Len(t, resp.Results, 3)
+		assert.Equal(t, batchResultOK, resp.Results[0].Result)
+		assert.Equal(t, batchResultInvalid, resp.Results[1].Result)
+		assert.Equal(t, batchResultOK, resp.Results[2].Result)
+
+		var count int
+		err := dbConnectionPool.GetContext(context.Background(), &count, "SELECT COUNT(*) FROM accounts WHERE stellar_address IN ($1, $2)", a, b)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		_, _ = dbConnectionPool.ExecContext(context.Background(), "TRUNCATE accounts CASCADE")
+	})
+
+	t.Run("replay_same_key_returns_cached_response", func(t *testing.T) {
+		address := keypair.MustRandom().Address()
+		payload := fmt.Sprintf(`{"addresses": [%q], "idempotency_key": "key-3"}`, address)
+
+		first := authedRequest(t, http.MethodPost, "/payments/subscribe/batch", payload, token)
+		rr1 := httptest.NewRecorder()
+		r.ServeHTTP(rr1, first)
+		require.Equal(t, http.StatusOK, rr1.Code)
+
+		second := authedRequest(t, http.MethodPost, "/payments/subscribe/batch", payload, token)
+		rr2 := httptest.NewRecorder()
+		r.ServeHTTP(rr2, second)
+		require.Equal(t, http.StatusOK, rr2.Code)
+		assert.Equal(t, rr1.Body.String(), rr2.Body.String())
+
+		_, _ = dbConnectionPool.ExecContext(context.Background(), "TRUNCATE accounts CASCADE")
+	})
+
+	t.Run("replay_same_key_different_body_conflicts", func(t *testing.T) {
+		addressA := keypair.MustRandom().Address()
+		addressB := keypair.MustRandom().Address()
+
+		first := authedRequest(t, http.MethodPost, "/payments/subscribe/batch",
+			fmt.Sprintf(`{"addresses": [%q], "idempotency_key": "key-4"}`, addressA), token)
+		rr1 := httptest.NewRecorder()
+		r.ServeHTTP(rr1, first)
+		require.Equal(t, http.StatusOK, rr1.Code)
+
+		second := authedRequest(t, http.MethodPost, "/payments/subscribe/batch",
+			fmt.Sprintf(`{"addresses": [%q], "idempotency_key": "key-4"}`, addressB), token)
+		rr2 := httptest.NewRecorder()
+		r.ServeHTTP(rr2, second)
+		assert.Equal(t, http.StatusConflict, rr2.Code)
+
+		_, _ = dbConnectionPool.ExecContext(context.Background(), "TRUNCATE accounts CASCADE")
+	})
+
+	t.Run("same_key_different_users_do_not_interfere", func(t *testing.T) {
+		otherToken := mintTestToken(t, dbConnectionPool)
+
+		addressA := keypair.MustRandom().Address()
+		addressB := keypair.MustRandom().Address()
+
+		first := authedRequest(t, http.MethodPost, "/payments/subscribe/batch",
+			fmt.Sprintf(`{"addresses": [%q], "idempotency_key": "shared-key"}`, addressA), token)
+		rr1 := httptest.NewRecorder()
+		r.ServeHTTP(rr1, first)
+		require.Equal(t, http.StatusOK, rr1.Code)
+
+		second := authedRequest(t, http.MethodPost, "/payments/subscribe/batch",
+			fmt.Sprintf(`{"addresses": [%q], "idempotency_key": "shared-key"}`, addressB), otherToken)
+		rr2 := httptest.NewRecorder()
+		r.ServeHTTP(rr2, second)
+		require.Equal(t, http.StatusOK, rr2.Code)
+
+		var resp2 batchResponse
+		require.NoError(t, json.NewDecoder(rr2.Body).Decode(&resp2))
+		require.Len(t, resp2.Results, 1)
+		assert.Equal(t, addressB, resp2.Results[0].Address)
+		assert.Equal(t, batchResultOK, resp2.Results[0].Result)
+
+		var count int
+		err := dbConnectionPool.GetContext(context.Background(), &count, "SELECT COUNT(*) FROM accounts WHERE stellar_address IN ($1, $2)", addressA, addressB)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		_, _ = dbConnectionPool.ExecContext(context.Background(), "TRUNCATE accounts CASCADE")
+	})
+
+	t.Run("concurrent_requests_same_key_do_not_double_commit", func(t *testing.T) {
+		addressA := keypair.MustRandom().Address()
+		addressB := keypair.MustRandom().Address()
+
+		payloadA := fmt.Sprintf(`{"addresses": [%q], "idempotency_key": "race-key"}`, addressA)
+		payloadB := fmt.Sprintf(`{"addresses": [%q], "idempotency_key": "race-key"}`, addressB)
+
+		var wg sync.WaitGroup
+		var codeA, codeB int
+		var bodyA, bodyB string
+		start := make(chan struct{})
+
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			<-start
+			req := authedRequest(t, http.MethodPost, "/payments/subscribe/batch", payloadA, token)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+			codeA, bodyA = rr.Code, rr.Body.String()
+		}()
+		go func() {
+			defer wg.Done()
+			<-start
+			req := authedRequest(t, http.MethodPost, "/payments/subscribe/batch", payloadB, token)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+			codeB, bodyB = rr.Code, rr.Body.String()
+		}()
+		close(start)
+		wg.Wait()
+
+		// Only one request can win the race to record the key. The bodies
+		// differ, so the loser is answered with a conflict rather than the
+		// winner's cached response.
+		assert.ElementsMatch(t, []int{http.StatusOK, http.StatusConflict}, []int{codeA, codeB})
+		winnerAddress, winnerBody := addressA, bodyA
+		if codeB == http.StatusOK {
+			winnerAddress, winnerBody = addressB, bodyB
+		}
+		assert.Contains(t, winnerBody, winnerAddress)
+
+		// Only the winner's address should have been subscribed; the
+		// loser's transaction must have been rolled back rather than also
+		// committing its mutations alongside the winner's cached response.
+		var count int
+		err := dbConnectionPool.GetContext(context.Background(), &count,
+			"SELECT COUNT(*) FROM accounts WHERE stellar_address IN ($1, $2)", addressA, addressB)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		_, _ = dbConnectionPool.ExecContext(context.Background(), "TRUNCATE accounts CASCADE")
+		_, _ = dbConnectionPool.ExecContext(context.Background(), "DELETE FROM idempotency_keys WHERE key = 'race-key'")
+	})
+}
+
+func TestUnsubscribeBatch(t *testing.T) {
+	r, dbConnectionPool, token := setupBatchRouter(t)
+
+	ctx := context.Background()
+	user, err := auth.NewUsersModel(dbConnectionPool).GetByToken(ctx, token)
+	require.NoError(t, err)
+
+	subscribed := keypair.MustRandom().Address()
+	_, err = dbConnectionPool.ExecContext(ctx, "INSERT INTO accounts (stellar_address, user_id) VALUES ($1, $2)", subscribed, user.ID)
+	require.NoError(t, err)
+
+	neverSubscribed := keypair.MustRandom().Address()
+
+	payload := fmt.Sprintf(`{"addresses": [%q, %q], "idempotency_key": "unsub-key-1"}`, subscribed, neverSubscribed)
+	req, err := http.NewRequest(http.MethodPost, "/payments/unsubscribe/batch", strings.NewReader(payload))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp batchResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Len(t, resp.Results, 2)
+	assert.Equal(t, batchResultOK, resp.Results[0].Result)
+	assert.Equal(t, batchResultNotSubscribed, resp.Results[1].Result)
+
+	var count sql.NullInt64
+	err = dbConnectionPool.GetContext(ctx, &count, "SELECT COUNT(*) FROM accounts WHERE stellar_address = $1", subscribed)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, count.Int64)
+}
@@ -0,0 +1,273 @@
+package httphandler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/wallet-backend/internal/auth"
+	"github.com/stellar/wallet-backend/internal/data"
+	"github.com/stellar/wallet-backend/internal/serve/httperror"
+	"github.com/stellar/wallet-backend/internal/webhooks"
+)
+
+// PaymentsHandler exposes the subscribe/unsubscribe endpoints used to manage
+// which Stellar addresses this backend indexes payments for.
+type PaymentsHandler struct {
+	Models *data.Models
+
+	// EnableSingleStepSubscribe allows POST /payments/subscribe to accept a
+	// bare {address} payload without a challenge/signature, skipping the
+	// proof-of-ownership check. It exists purely for local development and
+	// must stay disabled in production configs.
+	EnableSingleStepSubscribe bool
+}
+
+type subscribeChallengeRequest struct {
+	Address string `json:"address"`
+}
+
+type subscribeChallengeResponse struct {
+	ChallengeID string `json:"challenge_id"`
+	Nonce       string `json:"nonce"`
+	ExpiresAt   string `json:"expires_at"`
+}
+
+// SubscribeChallenge issues a short-lived nonce that the caller must sign
+// with the address's secret key to prove ownership before subscribing.
+func (h *PaymentsHandler) SubscribeChallenge(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req subscribeChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperror.NewValidationError(map[string]string{"address": "Invalid request body"}).Render(w)
+		return
+	}
+
+	if _, err := keypair.ParseAddress(req.Address); err != nil {
+		httperror.NewValidationError(map[string]string{"address": "Invalid public key provided"}).Render(w)
+		return
+	}
+
+	challenge, err := h.Models.SubscriptionChallenge.Insert(ctx, req.Address)
+	if err != nil {
+		httperror.NewInternalServerError().Render(w)
+		return
+	}
+
+	resp := subscribeChallengeResponse{
+		ChallengeID: challenge.ID,
+		Nonce:       challenge.Nonce,
+		ExpiresAt:   challenge.ExpiresAt.Format(httpTimeFormat),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+type subscribeAddressRequest struct {
+	Address     string `json:"address"`
+	ChallengeID string `json:"challenge_id"`
+	Signature   string `json:"signature"`
+	CallbackURL string `json:"callback_url"`
+	Secret      string `json:"secret"`
+}
+
+// SubscribeAddress subscribes a Stellar address so that the backend starts
+// indexing payments for it. Unless EnableSingleStepSubscribe is set, the
+// caller must first obtain a challenge from SubscribeChallenge and submit its
+// id along with an ed25519 signature of the nonce, proving they control
+// address's secret key.
+func (h *PaymentsHandler) SubscribeAddress(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req subscribeAddressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperror.NewValidationError(map[string]string{"address": "Invalid request body"}).Render(w)
+		return
+	}
+
+	kp, err := keypair.ParseAddress(req.Address)
+	if err != nil {
+		httperror.NewValidationError(map[string]string{"address": "Invalid public key provided"}).Render(w)
+		return
+	}
+
+	if req.ChallengeID == "" {
+		if !h.EnableSingleStepSubscribe {
+			httperror.NewValidationError(map[string]string{"challenge_id": "Challenge id is required"}).Render(w)
+			return
+		}
+	} else {
+		challenge, err := h.Models.SubscriptionChallenge.Get(ctx, req.ChallengeID, req.Address)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrChallengeNotFound):
+				httperror.NewValidationError(map[string]string{"challenge_id": "Unknown challenge"}).Render(w)
+			case errors.Is(err, data.ErrChallengeAlreadyUsed), errors.Is(err, data.ErrChallengeExpired):
+				httperror.NewValidationError(map[string]string{"challenge_id": "Challenge is expired or already used"}).Render(w)
+			default:
+				httperror.NewInternalServerError().Render(w)
+			}
+			return
+		}
+
+		sig, err := base64.StdEncoding.DecodeString(req.Signature)
+		if err != nil {
+			httperror.NewValidationError(map[string]string{"signature": "Invalid signature encoding"}).Render(w)
+			return
+		}
+
+		if err := kp.Verify([]byte(challenge.Nonce), sig); err != nil {
+			httperror.NewUnauthorizedError("Signature verification failed").Render(w)
+			return
+		}
+
+		// Only mark the challenge used once the signature has been confirmed
+		// valid, so a failed attempt (typo'd retry, or a third party racing
+		// a junk request) never burns the nonce out from under the
+		// legitimate holder. Consume's atomic UPDATE still guarantees that
+		// two concurrent requests with a correct signature can't both win.
+		if _, err := h.Models.SubscriptionChallenge.Consume(ctx, req.ChallengeID, req.Address); err != nil {
+			switch {
+			case errors.Is(err, data.ErrChallengeNotFound):
+				httperror.NewValidationError(map[string]string{"challenge_id": "Unknown challenge"}).Render(w)
+			case errors.Is(err, data.ErrChallengeAlreadyUsed), errors.Is(err, data.ErrChallengeExpired):
+				httperror.NewValidationError(map[string]string{"challenge_id": "Challenge is expired or already used"}).Render(w)
+			default:
+				httperror.NewInternalServerError().Render(w)
+			}
+			return
+		}
+	}
+
+	userID, ok := auth.UserIDFromContext(ctx)
+	if !ok {
+		httperror.NewInternalServerError().Render(w)
+		return
+	}
+
+	if _, err := h.Models.Account.InsertIfNotExists(ctx, userID, req.Address); err != nil {
+		httperror.NewInternalServerError().Render(w)
+		return
+	}
+
+	if req.CallbackURL != "" {
+		if req.Secret == "" {
+			httperror.NewValidationError(map[string]string{"secret": "Secret is required when callback_url is set"}).Render(w)
+			return
+		}
+
+		if err := webhooks.ValidateCallbackURL(req.CallbackURL); err != nil {
+			httperror.NewValidationError(map[string]string{"callback_url": "Must be an https url with a public host"}).Render(w)
+			return
+		}
+
+		if _, err := h.Models.SubscriptionWebhook.Insert(ctx, req.Address, req.CallbackURL, req.Secret); err != nil {
+			httperror.NewInternalServerError().Render(w)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+type unsubscribeAddressRequest struct {
+	Address string `json:"address"`
+}
+
+// UnsubscribeAddress stops the backend from indexing payments for address.
+// It is idempotent: unsubscribing an address that isn't subscribed succeeds.
+func (h *PaymentsHandler) UnsubscribeAddress(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req unsubscribeAddressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httperror.NewValidationError(map[string]string{"address": "Invalid request body"}).Render(w)
+		return
+	}
+
+	if _, err := keypair.ParseAddress(req.Address); err != nil {
+		httperror.NewValidationError(map[string]string{"address": "Invalid public key provided"}).Render(w)
+		return
+	}
+
+	userID, ok := auth.UserIDFromContext(ctx)
+	if !ok {
+		httperror.NewInternalServerError().Render(w)
+		return
+	}
+
+	if _, err := h.Models.Account.Delete(ctx, userID, req.Address); err != nil {
+		httperror.NewInternalServerError().Render(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+const (
+	httpTimeFormat            = "2006-01-02T15:04:05Z07:00"
+	defaultSubscriptionsLimit = 50
+	maxSubscriptionsLimit     = 200
+)
+
+type subscription struct {
+	Address   string `json:"address"`
+	CreatedAt string `json:"created_at"`
+}
+
+type listSubscriptionsResponse struct {
+	Subscriptions []subscription `json:"subscriptions"`
+}
+
+// ListSubscriptions returns the Stellar addresses subscribed by the
+// authenticated caller, paginated via `limit`/`offset` query params.
+func (h *PaymentsHandler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := auth.UserIDFromContext(ctx)
+	if !ok {
+		httperror.NewInternalServerError().Render(w)
+		return
+	}
+
+	limit := defaultSubscriptionsLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 || parsed > maxSubscriptionsLimit {
+			httperror.NewValidationError(map[string]string{"limit": "Must be an integer between 1 and 200"}).Render(w)
+			return
+		}
+		limit = parsed
+	}
+
+	offset := 0
+	if v := r.URL.Query().Get("offset"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 0 {
+			httperror.NewValidationError(map[string]string{"offset": "Must be a non-negative integer"}).Render(w)
+			return
+		}
+		offset = parsed
+	}
+
+	accounts, err := h.Models.Account.ListByUser(ctx, userID, limit, offset)
+	if err != nil {
+		httperror.NewInternalServerError().Render(w)
+		return
+	}
+
+	resp := listSubscriptionsResponse{Subscriptions: make([]subscription, len(accounts))}
+	for i, account := range accounts {
+		resp.Subscriptions[i] = subscription{
+			Address:   account.StellarAddress,
+			CreatedAt: account.CreatedAt.Format(httpTimeFormat),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -3,6 +3,8 @@ package httphandler
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,6 +14,7 @@ import (
 
 	"github.com/go-chi/chi"
 	"github.com/stellar/go/keypair"
+	"github.com/stellar/wallet-backend/internal/auth"
 	"github.com/stellar/wallet-backend/internal/data"
 	"github.com/stellar/wallet-backend/internal/db"
 	"github.com/stellar/wallet-backend/internal/db/dbtest"
@@ -19,6 +22,43 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// mintTestToken creates a throwaway API user and returns its bearer token,
+// for use as the Authorization header in handler tests.
+func mintTestToken(t *testing.T, dbConnectionPool db.DBConnectionPool) string {
+	t.Helper()
+
+	token, err := auth.NewUsersModel(dbConnectionPool).Create(context.Background(), fmt.Sprintf("%s@example.com", keypair.MustRandom().Address()))
+	require.NoError(t, err)
+	return token
+}
+
+func authedRequest(t *testing.T, method, target, body, token string) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequest(method, target, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+// requestChallenge drives the /payments/subscribe/challenge endpoint and
+// returns the issued challenge id + nonce for address.
+func requestChallenge(t *testing.T, r *chi.Mux, token, address string) (challengeID string, nonce string) {
+	t.Helper()
+
+	payload := fmt.Sprintf(`{ "address": %q }`, address)
+	req := authedRequest(t, http.MethodPost, "/payments/subscribe/challenge", payload, token)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp subscribeChallengeResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+
+	return resp.ChallengeID, resp.Nonce
+}
+
 func TestSubscribeAddress(t *testing.T) {
 	dbt := dbtest.Open(t)
 	defer dbt.Close()
@@ -35,25 +75,35 @@ func TestSubscribeAddress(t *testing.T) {
 
 	// Setup router
 	r := chi.NewRouter()
+	r.Use(auth.Middleware(auth.NewUsersModel(dbConnectionPool)))
+	r.Post("/payments/subscribe/challenge", handler.SubscribeChallenge)
 	r.Post("/payments/subscribe", handler.SubscribeAddress)
 
+	token := mintTestToken(t, dbConnectionPool)
+
 	clearAccounts := func(ctx context.Context) {
-		_, err = dbConnectionPool.ExecContext(ctx, "TRUNCATE accounts")
+		_, err = dbConnectionPool.ExecContext(ctx, "TRUNCATE accounts CASCADE")
 		require.NoError(t, err)
 	}
 
-	t.Run("success_happy_path", func(t *testing.T) {
-		// Prepare request
-		address := keypair.MustRandom().Address()
-		payload := fmt.Sprintf(`{ "address": %q }`, address)
-		req, err := http.NewRequest(http.MethodPost, "/payments/subscribe", strings.NewReader(payload))
+	subscribe := func(t *testing.T, kp *keypair.Full, challengeID, nonce string) *httptest.ResponseRecorder {
+		sig, err := kp.Sign([]byte(nonce))
 		require.NoError(t, err)
 
-		// Serve request
+		payload := fmt.Sprintf(`{ "address": %q, "challenge_id": %q, "signature": %q }`,
+			kp.Address(), challengeID, base64.StdEncoding.EncodeToString(sig))
+		req := authedRequest(t, http.MethodPost, "/payments/subscribe", payload, token)
+
 		rr := httptest.NewRecorder()
 		r.ServeHTTP(rr, req)
+		return rr
+	}
 
-		// Assert 200 response
+	t.Run("success_happy_path", func(t *testing.T) {
+		kp := keypair.MustRandom()
+		challengeID, nonce := requestChallenge(t, r, token, kp.Address())
+
+		rr := subscribe(t, kp, challengeID, nonce)
 		assert.Equal(t, http.StatusOK, rr.Code)
 
 		ctx := context.Background()
@@ -63,49 +113,177 @@ func TestSubscribeAddress(t *testing.T) {
 
 		// Assert address persisted in DB
 		assert.True(t, dbAddress.Valid)
-		assert.Equal(t, address, dbAddress.String)
+		assert.Equal(t, kp.Address(), dbAddress.String)
 
 		clearAccounts(ctx)
 	})
 
-	t.Run("address_already_exists", func(t *testing.T) {
-		address := keypair.MustRandom().Address()
+	t.Run("success_with_callback_url_persists_webhook", func(t *testing.T) {
+		kp := keypair.MustRandom()
+		challengeID, nonce := requestChallenge(t, r, token, kp.Address())
+
+		sig, err := kp.Sign([]byte(nonce))
+		require.NoError(t, err)
+
+		payload := fmt.Sprintf(
+			`{ "address": %q, "challenge_id": %q, "signature": %q, "callback_url": "https://example.com/hook", "secret": "s3cr3t" }`,
+			kp.Address(), challengeID, base64.StdEncoding.EncodeToString(sig))
+		req := authedRequest(t, http.MethodPost, "/payments/subscribe", payload, token)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
 		ctx := context.Background()
+		webhooks, err := models.SubscriptionWebhook.GetByAddress(ctx, kp.Address())
+		require.NoError(t, err)
+		require.Len(t, webhooks, 1)
+		assert.Equal(t, "https://example.com/hook", webhooks[0].CallbackURL)
 
-		// Insert address in DB
-		_, err = dbConnectionPool.ExecContext(ctx, "INSERT INTO accounts (stellar_address) VALUES ($1)", address)
+		clearAccounts(ctx)
+	})
+
+	t.Run("rejects_callback_url_targeting_private_host", func(t *testing.T) {
+		kp := keypair.MustRandom()
+		challengeID, nonce := requestChallenge(t, r, token, kp.Address())
+
+		sig, err := kp.Sign([]byte(nonce))
 		require.NoError(t, err)
 
-		// Prepare request
-		payload := fmt.Sprintf(`{ "address": %q }`, address)
-		req, err := http.NewRequest(http.MethodPost, "/payments/subscribe", strings.NewReader(payload))
+		payload := fmt.Sprintf(
+			`{ "address": %q, "challenge_id": %q, "signature": %q, "callback_url": "https://169.254.169.254/latest/meta-data", "secret": "s3cr3t" }`,
+			kp.Address(), challengeID, base64.StdEncoding.EncodeToString(sig))
+		req := authedRequest(t, http.MethodPost, "/payments/subscribe", payload, token)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		ctx := context.Background()
+		webhooks, err := models.SubscriptionWebhook.GetByAddress(ctx, kp.Address())
+		require.NoError(t, err)
+		assert.Empty(t, webhooks)
+
+		clearAccounts(ctx)
+	})
+
+	t.Run("rejects_non_https_callback_url", func(t *testing.T) {
+		kp := keypair.MustRandom()
+		challengeID, nonce := requestChallenge(t, r, token, kp.Address())
+
+		sig, err := kp.Sign([]byte(nonce))
+		require.NoError(t, err)
+
+		payload := fmt.Sprintf(
+			`{ "address": %q, "challenge_id": %q, "signature": %q, "callback_url": "http://example.com/hook", "secret": "s3cr3t" }`,
+			kp.Address(), challengeID, base64.StdEncoding.EncodeToString(sig))
+		req := authedRequest(t, http.MethodPost, "/payments/subscribe", payload, token)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		clearAccounts(context.Background())
+	})
+
+	t.Run("wrong_key_signature", func(t *testing.T) {
+		owner := keypair.MustRandom()
+		impostor := keypair.MustRandom()
+		challengeID, nonce := requestChallenge(t, r, token, owner.Address())
+
+		sig, err := impostor.Sign([]byte(nonce))
 		require.NoError(t, err)
 
-		// Serve request
+		payload := fmt.Sprintf(`{ "address": %q, "challenge_id": %q, "signature": %q }`,
+			owner.Address(), challengeID, base64.StdEncoding.EncodeToString(sig))
+		req := authedRequest(t, http.MethodPost, "/payments/subscribe", payload, token)
+
 		rr := httptest.NewRecorder()
 		r.ServeHTTP(rr, req)
 
-		// Assert 200 response
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("reused_challenge", func(t *testing.T) {
+		kp := keypair.MustRandom()
+		challengeID, nonce := requestChallenge(t, r, token, kp.Address())
+
+		first := subscribe(t, kp, challengeID, nonce)
+		require.Equal(t, http.StatusOK, first.Code)
+
+		second := subscribe(t, kp, challengeID, nonce)
+		assert.Equal(t, http.StatusBadRequest, second.Code)
+
+		clearAccounts(context.Background())
+	})
+
+	t.Run("expired_challenge", func(t *testing.T) {
+		kp := keypair.MustRandom()
+		challengeID, nonce := requestChallenge(t, r, token, kp.Address())
+
+		ctx := context.Background()
+		_, err = dbConnectionPool.ExecContext(ctx,
+			"UPDATE subscription_challenges SET expires_at = NOW() - INTERVAL '1 minute' WHERE id = $1", challengeID)
+		require.NoError(t, err)
+
+		rr := subscribe(t, kp, challengeID, nonce)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("failed_signature_does_not_burn_challenge_for_later_retry", func(t *testing.T) {
+		kp := keypair.MustRandom()
+		challengeID, nonce := requestChallenge(t, r, token, kp.Address())
+
+		badSig, err := kp.Sign([]byte(nonce + "tampered"))
+		require.NoError(t, err)
+		badPayload := fmt.Sprintf(`{ "address": %q, "challenge_id": %q, "signature": %q }`,
+			kp.Address(), challengeID, base64.StdEncoding.EncodeToString(badSig))
+		badReq := authedRequest(t, http.MethodPost, "/payments/subscribe", badPayload, token)
+
+		badRR := httptest.NewRecorder()
+		r.ServeHTTP(badRR, badReq)
+		require.Equal(t, http.StatusUnauthorized, badRR.Code)
+
+		// The same challenge, now signed correctly, must still succeed: a
+		// bad signature attempt should not have consumed the nonce.
+		rr := subscribe(t, kp, challengeID, nonce)
 		assert.Equal(t, http.StatusOK, rr.Code)
 
-		var dbAddress sql.NullString
-		err = dbConnectionPool.GetContext(ctx, &dbAddress, "SELECT stellar_address FROM accounts")
+		clearAccounts(context.Background())
+	})
+
+	t.Run("tampered_nonce", func(t *testing.T) {
+		kp := keypair.MustRandom()
+		challengeID, nonce := requestChallenge(t, r, token, kp.Address())
+
+		sig, err := kp.Sign([]byte(nonce + "tampered"))
 		require.NoError(t, err)
 
-		// Assert address persisted in DB
-		assert.True(t, dbAddress.Valid)
-		assert.Equal(t, address, dbAddress.String)
+		payload := fmt.Sprintf(`{ "address": %q, "challenge_id": %q, "signature": %q }`,
+			kp.Address(), challengeID, base64.StdEncoding.EncodeToString(sig))
+		req := authedRequest(t, http.MethodPost, "/payments/subscribe", payload, token)
 
-		clearAccounts(ctx)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("missing_challenge_id_rejected_by_default", func(t *testing.T) {
+		address := keypair.MustRandom().Address()
+		payload := fmt.Sprintf(`{ "address": %q }`, address)
+		req := authedRequest(t, http.MethodPost, "/payments/subscribe", payload, token)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
 	})
 
 	t.Run("invalid_address", func(t *testing.T) {
-		// Prepare request
 		payload := fmt.Sprintf(`{ "address": %q }`, "invalid")
-		req, err := http.NewRequest(http.MethodPost, "/payments/subscribe", strings.NewReader(payload))
-		require.NoError(t, err)
+		req := authedRequest(t, http.MethodPost, "/payments/subscribe", payload, token)
 
-		// Serve request
 		rr := httptest.NewRecorder()
 		r.ServeHTTP(rr, req)
 
@@ -116,6 +294,80 @@ func TestSubscribeAddress(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
 		assert.JSONEq(t, `{"error":"Validation error.", "extras": {"address":"Invalid public key provided"}}`, string(respBody))
 	})
+
+	t.Run("missing_token", func(t *testing.T) {
+		address := keypair.MustRandom().Address()
+		payload := fmt.Sprintf(`{ "address": %q }`, address)
+		req, err := http.NewRequest(http.MethodPost, "/payments/subscribe", strings.NewReader(payload))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("invalid_token", func(t *testing.T) {
+		address := keypair.MustRandom().Address()
+		payload := fmt.Sprintf(`{ "address": %q }`, address)
+		req := authedRequest(t, http.MethodPost, "/payments/subscribe", payload, "not-a-real-token")
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("revoked_token", func(t *testing.T) {
+		revokedEmail := "revoked-subscribe@example.com"
+		revokedToken, err := auth.NewUsersModel(dbConnectionPool).Create(context.Background(), revokedEmail)
+		require.NoError(t, err)
+		_, err = dbConnectionPool.ExecContext(context.Background(),
+			"UPDATE users SET revoked_at = NOW() WHERE email = $1", revokedEmail)
+		require.NoError(t, err)
+
+		address := keypair.MustRandom().Address()
+		payload := fmt.Sprintf(`{ "address": %q }`, address)
+		req := authedRequest(t, http.MethodPost, "/payments/subscribe", payload, revokedToken)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
+
+func TestSubscribeAddressSingleStepDev(t *testing.T) {
+	dbt := dbtest.Open(t)
+	defer dbt.Close()
+
+	dbConnectionPool, err := db.OpenDBConnectionPool(dbt.DSN)
+	require.NoError(t, err)
+	defer dbConnectionPool.Close()
+
+	models, err := data.NewModels(dbConnectionPool)
+	require.NoError(t, err)
+	handler := &PaymentsHandler{
+		Models:                    models,
+		EnableSingleStepSubscribe: true,
+	}
+
+	r := chi.NewRouter()
+	r.Use(auth.Middleware(auth.NewUsersModel(dbConnectionPool)))
+	r.Post("/payments/subscribe", handler.SubscribeAddress)
+
+	token := mintTestToken(t, dbConnectionPool)
+
+	t.Run("success_without_challenge", func(t *testing.T) {
+		address := keypair.MustRandom().Address()
+		payload := fmt.Sprintf(`{ "address": %q }`, address)
+		req := authedRequest(t, http.MethodPost, "/payments/subscribe", payload, token)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
 }
 
 func TestUnsubscribeAddress(t *testing.T) {
@@ -134,29 +386,36 @@ func TestUnsubscribeAddress(t *testing.T) {
 
 	// Setup router
 	r := chi.NewRouter()
+	r.Use(auth.Middleware(auth.NewUsersModel(dbConnectionPool)))
 	r.Post("/payments/unsubscribe", handler.UnsubscribeAddress)
 
+	token := mintTestToken(t, dbConnectionPool)
+
+	userIDFor := func(ctx context.Context, token string) string {
+		user, err := auth.NewUsersModel(dbConnectionPool).GetByToken(ctx, token)
+		require.NoError(t, err)
+		return user.ID
+	}
+
 	t.Run("successHappyPath", func(t *testing.T) {
 		address := keypair.MustRandom().Address()
 		ctx := context.Background()
 
-		// Insert address in DB
-		_, err = dbConnectionPool.ExecContext(ctx, "INSERT INTO accounts (stellar_address) VALUES ($1)", address)
+		// Insert address in DB, owned by the test user
+		_, err = dbConnectionPool.ExecContext(ctx, "INSERT INTO accounts (stellar_address, user_id) VALUES ($1, $2)",
+			address, userIDFor(ctx, token))
 		require.NoError(t, err)
 
-		// Prepare request
 		payload := fmt.Sprintf(`{ "address": %q }`, address)
-		req, err := http.NewRequest(http.MethodPost, "/payments/unsubscribe", strings.NewReader(payload))
-		require.NoError(t, err)
+		req := authedRequest(t, http.MethodPost, "/payments/unsubscribe", payload, token)
 
-		// Serve request
 		rr := httptest.NewRecorder()
 		r.ServeHTTP(rr, req)
 
 		// Assert 200 response
 		assert.Equal(t, http.StatusOK, rr.Code)
 
-		// Assert no address no longer in DB
+		// Assert address no longer in DB
 		var dbAddress sql.NullString
 		err = dbConnectionPool.GetContext(ctx, &dbAddress, "SELECT stellar_address FROM accounts")
 		assert.ErrorIs(t, err, sql.ErrNoRows)
@@ -170,26 +429,19 @@ func TestUnsubscribeAddress(t *testing.T) {
 		_, err = dbConnectionPool.ExecContext(ctx, "DELETE FROM accounts")
 		require.NoError(t, err)
 
-		// Prepare request
 		payload := fmt.Sprintf(`{ "address": %q }`, address)
-		req, err := http.NewRequest(http.MethodPost, "/payments/unsubscribe", strings.NewReader(payload))
-		require.NoError(t, err)
+		req := authedRequest(t, http.MethodPost, "/payments/unsubscribe", payload, token)
 
-		// Serve request
 		rr := httptest.NewRecorder()
 		r.ServeHTTP(rr, req)
 
-		// Assert 200 response
 		assert.Equal(t, http.StatusOK, rr.Code)
 	})
 
 	t.Run("invalid_address", func(t *testing.T) {
-		// Prepare request
 		payload := fmt.Sprintf(`{ "address": %q }`, "invalid")
-		req, err := http.NewRequest(http.MethodPost, "/payments/unsubscribe", strings.NewReader(payload))
-		require.NoError(t, err)
+		req := authedRequest(t, http.MethodPost, "/payments/unsubscribe", payload, token)
 
-		// Serve request
 		rr := httptest.NewRecorder()
 		r.ServeHTTP(rr, req)
 
@@ -200,4 +452,65 @@ func TestUnsubscribeAddress(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
 		assert.JSONEq(t, `{"error":"Validation error.", "extras": {"address":"Invalid public key provided"}}`, string(respBody))
 	})
+
+	t.Run("missing_token", func(t *testing.T) {
+		payload := fmt.Sprintf(`{ "address": %q }`, keypair.MustRandom().Address())
+		req, err := http.NewRequest(http.MethodPost, "/payments/unsubscribe", strings.NewReader(payload))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
+
+func TestListSubscriptions(t *testing.T) {
+	dbt := dbtest.Open(t)
+	defer dbt.Close()
+
+	dbConnectionPool, err := db.OpenDBConnectionPool(dbt.DSN)
+	require.NoError(t, err)
+	defer dbConnectionPool.Close()
+
+	models, err := data.NewModels(dbConnectionPool)
+	require.NoError(t, err)
+	handler := &PaymentsHandler{
+		Models: models,
+	}
+
+	r := chi.NewRouter()
+	r.Use(auth.Middleware(auth.NewUsersModel(dbConnectionPool)))
+	r.Get("/payments/subscriptions", handler.ListSubscriptions)
+
+	token := mintTestToken(t, dbConnectionPool)
+	ctx := context.Background()
+	user, err := auth.NewUsersModel(dbConnectionPool).GetByToken(ctx, token)
+	require.NoError(t, err)
+
+	otherToken := mintTestToken(t, dbConnectionPool)
+	otherUser, err := auth.NewUsersModel(dbConnectionPool).GetByToken(ctx, otherToken)
+	require.NoError(t, err)
+
+	mine := keypair.MustRandom().Address()
+	theirs := keypair.MustRandom().Address()
+	_, err = dbConnectionPool.ExecContext(ctx, "INSERT INTO accounts (stellar_address, user_id) VALUES ($1, $2)", mine, user.ID)
+	require.NoError(t, err)
+	_, err = dbConnectionPool.ExecContext(ctx, "INSERT INTO accounts (stellar_address, user_id) VALUES ($1, $2)", theirs, otherUser.ID)
+	require.NoError(t, err)
+
+	t.Run("only_returns_callers_subscriptions", func(t *testing.T) {
+		req := authedRequest(t, http.MethodGet, "/payments/subscriptions", "", token)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp listSubscriptionsResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+
+		require.Len(t, resp.Subscriptions, 1)
+		assert.Equal(t, mine, resp.Subscriptions[0].Address)
+	})
 }
@@ -0,0 +1,105 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/stellar/wallet-backend/internal/data"
+	"github.com/stellar/wallet-backend/internal/serve/httperror"
+)
+
+// WebhooksAdminHandler exposes operator endpoints for inspecting and
+// retrying webhook deliveries that have exhausted their automatic retries.
+// These expose delivery history and payload data across all tenants, so
+// routes must be mounted behind auth.AdminMiddleware rather than the
+// per-user auth.Middleware.
+type WebhooksAdminHandler struct {
+	Models *data.Models
+}
+
+type webhookDeliveryResponse struct {
+	ID            string `json:"id"`
+	WebhookID     string `json:"webhook_id"`
+	TxHash        string `json:"tx_hash"`
+	Status        string `json:"status"`
+	Attempts      int    `json:"attempts"`
+	NextAttemptAt string `json:"next_attempt_at"`
+	LastError     string `json:"last_error,omitempty"`
+}
+
+func toWebhookDeliveryResponse(d data.WebhookDelivery) webhookDeliveryResponse {
+	return webhookDeliveryResponse{
+		ID:            d.ID,
+		WebhookID:     d.WebhookID,
+		TxHash:        d.TxHash,
+		Status:        d.Status,
+		Attempts:      d.Attempts,
+		NextAttemptAt: d.NextAttemptAt.Format(httpTimeFormat),
+		LastError:     d.LastError.String,
+	}
+}
+
+// ListFailedDeliveries returns deliveries that have stopped retrying
+// automatically, paginated via `limit`/`offset` query params.
+func (h *WebhooksAdminHandler) ListFailedDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit := defaultSubscriptionsLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 || parsed > maxSubscriptionsLimit {
+			httperror.NewValidationError(map[string]string{"limit": "Must be an integer between 1 and 200"}).Render(w)
+			return
+		}
+		limit = parsed
+	}
+
+	offset := 0
+	if v := r.URL.Query().Get("offset"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 0 {
+			httperror.NewValidationError(map[string]string{"offset": "Must be a non-negative integer"}).Render(w)
+			return
+		}
+		offset = parsed
+	}
+
+	deliveries, err := h.Models.WebhookDelivery.ListFailed(ctx, limit, offset)
+	if err != nil {
+		httperror.NewInternalServerError().Render(w)
+		return
+	}
+
+	resp := make([]webhookDeliveryResponse, len(deliveries))
+	for i, d := range deliveries {
+		resp[i] = toWebhookDeliveryResponse(d)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// RetryDelivery forces an immediate retry of a dead or failed delivery.
+func (h *WebhooksAdminHandler) RetryDelivery(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	if _, err := h.Models.WebhookDelivery.Get(ctx, id); err != nil {
+		if errors.Is(err, data.ErrWebhookDeliveryNotFound) {
+			httperror.NewValidationError(map[string]string{"id": "Unknown delivery"}).Render(w)
+		} else {
+			httperror.NewInternalServerError().Render(w)
+		}
+		return
+	}
+
+	if err := h.Models.WebhookDelivery.ForceRetry(ctx, id); err != nil {
+		httperror.NewInternalServerError().Render(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
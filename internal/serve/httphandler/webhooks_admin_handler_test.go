@@ -0,0 +1,122 @@
+package httphandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/wallet-backend/internal/auth"
+	"github.com/stellar/wallet-backend/internal/data"
+	"github.com/stellar/wallet-backend/internal/db"
+	"github.com/stellar/wallet-backend/internal/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAdminToken = "admin-s3cr3t"
+
+func setupWebhooksAdminRouter(t *testing.T) (*chi.Mux, db.DBConnectionPool, *data.Models) {
+	t.Helper()
+
+	dbt := dbtest.Open(t)
+	t.Cleanup(dbt.Close)
+
+	dbConnectionPool, err := db.OpenDBConnectionPool(dbt.DSN)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = dbConnectionPool.Close() })
+
+	models, err := data.NewModels(dbConnectionPool)
+	require.NoError(t, err)
+	handler := &WebhooksAdminHandler{Models: models}
+
+	r := chi.NewRouter()
+	r.Use(auth.AdminMiddleware(testAdminToken))
+	r.Get("/admin/webhooks/deliveries/failed", handler.ListFailedDeliveries)
+	r.Post("/admin/webhooks/deliveries/{id}/retry", handler.RetryDelivery)
+
+	return r, dbConnectionPool, models
+}
+
+func seedFailedDelivery(t *testing.T, dbConnectionPool db.DBConnectionPool, models *data.Models) *data.WebhookDelivery {
+	t.Helper()
+
+	ctx := context.Background()
+	address := keypair.MustRandom().Address()
+	_, err := dbConnectionPool.ExecContext(ctx, "INSERT INTO accounts (stellar_address) VALUES ($1)", address)
+	require.NoError(t, err)
+
+	webhook, err := models.SubscriptionWebhook.Insert(ctx, address, "https://example.com/hook", "s3cr3t")
+	require.NoError(t, err)
+
+	delivery, err := models.WebhookDelivery.InsertIfNotExists(ctx, webhook.ID, "tx-hash-1", []byte(`{}`))
+	require.NoError(t, err)
+
+	err = models.WebhookDelivery.MarkFailed(ctx, delivery.ID, 1, nil, errors.New("seeded failure"))
+	require.NoError(t, err)
+
+	delivery, err = models.WebhookDelivery.Get(ctx, delivery.ID)
+	require.NoError(t, err)
+
+	return delivery
+}
+
+func TestWebhooksAdminHandlerAuth(t *testing.T) {
+	r, dbConnectionPool, models := setupWebhooksAdminRouter(t)
+	seedFailedDelivery(t, dbConnectionPool, models)
+
+	t.Run("missing_token_rejected", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/webhooks/deliveries/failed", nil)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("wrong_token_rejected", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/webhooks/deliveries/failed", nil)
+		req.Header.Set("Authorization", "Bearer not-the-admin-token")
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("valid_admin_token_allowed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/webhooks/deliveries/failed", nil)
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp []webhookDeliveryResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	})
+}
+
+func TestRetryDeliveryRequiresAdminAuth(t *testing.T) {
+	r, dbConnectionPool, models := setupWebhooksAdminRouter(t)
+	delivery := seedFailedDelivery(t, dbConnectionPool, models)
+
+	t.Run("missing_token_rejected", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/webhooks/deliveries/"+delivery.ID+"/retry", nil)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("valid_admin_token_allowed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/webhooks/deliveries/"+delivery.ID+"/retry", nil)
+		req.Header.Set("Authorization", "Bearer "+testAdminToken)
+
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
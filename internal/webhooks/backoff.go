@@ -0,0 +1,45 @@
+package webhooks
+
+import "time"
+
+// backoffSchedule is the retry ladder for the first few delivery attempts.
+var backoffSchedule = []time.Duration{
+	1 * time.Second,
+	5 * time.Second,
+	30 * time.Second,
+	5 * time.Minute,
+	1 * time.Hour,
+}
+
+// maxRetryWindow is the total time a delivery keeps being retried before
+// it's marked dead.
+const maxRetryWindow = 24 * time.Hour
+
+// NextBackoff returns the delay before attempt number `attempt` (1-indexed),
+// following backoffSchedule and then doubling the last step, capped at
+// maxRetryWindow. ok is false once the delivery has been retried for longer
+// than maxRetryWindow and should be marked dead instead.
+func NextBackoff(attempt int, elapsed time.Duration) (delay time.Duration, ok bool) {
+	if elapsed >= maxRetryWindow {
+		return 0, false
+	}
+
+	if attempt <= len(backoffSchedule) {
+		delay = backoffSchedule[attempt-1]
+	} else {
+		delay = backoffSchedule[len(backoffSchedule)-1]
+		for i := len(backoffSchedule); i < attempt; i++ {
+			delay *= 2
+			if delay >= maxRetryWindow {
+				delay = maxRetryWindow
+				break
+			}
+		}
+	}
+
+	if elapsed+delay > maxRetryWindow {
+		delay = maxRetryWindow - elapsed
+	}
+
+	return delay, true
+}
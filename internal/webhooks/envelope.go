@@ -0,0 +1,24 @@
+// Package webhooks delivers payment notifications to the callback URLs
+// registered on a subscribed address, with signed bodies and retries.
+package webhooks
+
+// Envelope is the JSON body POSTed to a registered callback URL. ID is the
+// delivery's own UUID, stable across retries, so receivers can dedupe.
+type Envelope struct {
+	ID        string `json:"id"`
+	Address   string `json:"address"`
+	TxHash    string `json:"tx_hash"`
+	Amount    string `json:"amount"`
+	Asset     string `json:"asset"`
+	CreatedAt string `json:"created_at"`
+}
+
+// PaymentEvent describes a payment observed on a subscribed address, as
+// reported by the payment indexer.
+type PaymentEvent struct {
+	Address   string
+	TxHash    string
+	Amount    string
+	Asset     string
+	CreatedAt string
+}
@@ -0,0 +1,18 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// SignatureHeader is the HTTP header carrying the HMAC-SHA256 signature of
+// the delivery body, hex-encoded.
+const SignatureHeader = "X-WalletBackend-Signature"
+
+// Sign computes the hex-encoded HMAC-SHA256 of body under secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
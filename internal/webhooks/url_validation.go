@@ -0,0 +1,53 @@
+package webhooks
+
+import (
+	"errors"
+	"net"
+	"net/url"
+)
+
+// ErrCallbackURLInvalid is returned by ValidateCallbackURL when the URL is
+// malformed or not https.
+var ErrCallbackURLInvalid = errors.New("callback url must be a valid https url")
+
+// ErrCallbackURLNotAllowed is returned by ValidateCallbackURL when the URL's
+// host is a literal loopback, link-local, or other private address that the
+// worker should never be tricked into delivering to.
+var ErrCallbackURLNotAllowed = errors.New("callback url host is not allowed")
+
+// ValidateCallbackURL rejects callback URLs that could be used to make the
+// webhook worker send requests to internal or link-local targets (SSRF). It
+// requires https and, when the host is a literal IP address, rejects
+// loopback, private, link-local, and unspecified ranges.
+//
+// This deliberately does not resolve hostnames: a hostname can be repointed
+// to an internal address any time after it passes this check, so a caller
+// could still register something that only resolves to a private IP at
+// delivery time. That's why Worker's http.Client also validates the
+// resolved address on every dial (see dialValidated in worker.go) — this
+// check only exists to reject obviously-bad URLs eagerly, at subscribe time.
+func ValidateCallbackURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ErrCallbackURLInvalid
+	}
+
+	if u.Scheme != "https" {
+		return ErrCallbackURLInvalid
+	}
+
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil && isDisallowedIP(ip) {
+		return ErrCallbackURLNotAllowed
+	}
+
+	return nil
+}
+
+func isDisallowedIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsPrivate() ||
+		ip.IsUnspecified()
+}
@@ -0,0 +1,189 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/stellar/go/support/log"
+	"github.com/stellar/wallet-backend/internal/data"
+)
+
+// DefaultBatchSize is how many due deliveries Worker.ProcessDue handles per
+// poll.
+const DefaultBatchSize = 100
+
+// Worker delivers payment events to registered webhooks and retries failed
+// deliveries with backoff until they succeed or exceed the retry window.
+type Worker struct {
+	Models     *data.Models
+	HTTPClient *http.Client
+}
+
+// NewWorker builds a Worker with a sane default HTTP client. The client's
+// transport re-validates the resolved address on every dial, so a callback
+// hostname can't evade ValidateCallbackURL by resolving to a private or
+// loopback address after subscription time.
+func NewWorker(models *data.Models) *Worker {
+	return &Worker{
+		Models: models,
+		HTTPClient: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{DialContext: dialValidated},
+		},
+	}
+}
+
+// dialValidated resolves addr's host and refuses to dial if any resulting IP
+// is loopback, link-local, private, or unspecified, closing the gap left by
+// ValidateCallbackURL only checking literal IPs at subscribe time.
+func dialValidated(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("splitting host/port for %q: %w", addr, err)
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %q: %w", host, err)
+	}
+
+	for _, ipAddr := range ips {
+		if isDisallowedIP(ipAddr.IP) {
+			return nil, fmt.Errorf("refusing to dial disallowed address %s for host %q", ipAddr.IP, host)
+		}
+	}
+
+	var dialer net.Dialer
+	var lastErr error
+	for _, ipAddr := range ips {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ipAddr.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("dialing %q: %w", host, lastErr)
+}
+
+// Run polls for due deliveries every interval until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.ProcessDue(ctx, DefaultBatchSize); err != nil {
+				log.Ctx(ctx).Errorf("processing due webhook deliveries: %v", err)
+			}
+		}
+	}
+}
+
+// HandlePaymentEvent enqueues a delivery for every webhook registered on
+// event.Address. Enqueuing is idempotent: replaying the same event for a
+// webhook reuses the existing delivery row instead of creating a duplicate.
+func (w *Worker) HandlePaymentEvent(ctx context.Context, event PaymentEvent) error {
+	webhookList, err := w.Models.SubscriptionWebhook.GetByAddress(ctx, event.Address)
+	if err != nil {
+		return fmt.Errorf("fetching webhooks for %q: %w", event.Address, err)
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshaling payment event: %w", err)
+	}
+
+	for _, webhook := range webhookList {
+		if _, err := w.Models.WebhookDelivery.InsertIfNotExists(ctx, webhook.ID, event.TxHash, payload); err != nil {
+			return fmt.Errorf("enqueuing delivery for webhook %q: %w", webhook.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// ProcessDue attempts delivery of up to limit deliveries that are due for a
+// (re)try.
+func (w *Worker) ProcessDue(ctx context.Context, limit int) error {
+	deliveries, err := w.Models.WebhookDelivery.ListDue(ctx, limit)
+	if err != nil {
+		return fmt.Errorf("listing due webhook deliveries: %w", err)
+	}
+
+	for _, delivery := range deliveries {
+		if err := w.attempt(ctx, delivery); err != nil {
+			log.Ctx(ctx).Errorf("delivering webhook delivery %q: %v", delivery.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (w *Worker) attempt(ctx context.Context, delivery data.WebhookDelivery) error {
+	webhook, err := w.Models.SubscriptionWebhook.GetByID(ctx, delivery.WebhookID)
+	if err != nil {
+		return fmt.Errorf("fetching webhook %q: %w", delivery.WebhookID, err)
+	}
+
+	var event PaymentEvent
+	if err := json.Unmarshal(delivery.Payload, &event); err != nil {
+		return fmt.Errorf("unmarshaling payload for delivery %q: %w", delivery.ID, err)
+	}
+
+	body, err := json.Marshal(Envelope{
+		ID:        delivery.ID,
+		Address:   webhook.StellarAddress,
+		TxHash:    delivery.TxHash,
+		Amount:    event.Amount,
+		Asset:     event.Asset,
+		CreatedAt: event.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("marshaling envelope for delivery %q: %w", delivery.ID, err)
+	}
+
+	deliveryErr := w.post(ctx, webhook.CallbackURL, webhook.Secret, body)
+
+	attempts := delivery.Attempts + 1
+	if deliveryErr == nil {
+		return w.Models.WebhookDelivery.MarkSucceeded(ctx, delivery.ID)
+	}
+
+	delay, ok := NextBackoff(attempts, time.Since(delivery.CreatedAt))
+	if !ok {
+		return w.Models.WebhookDelivery.MarkFailed(ctx, delivery.ID, attempts, nil, deliveryErr)
+	}
+
+	nextAttemptAt := time.Now().Add(delay)
+	return w.Models.WebhookDelivery.MarkFailed(ctx, delivery.ID, attempts, &nextAttemptAt, deliveryErr)
+}
+
+func (w *Worker) post(ctx context.Context, url, secret string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("building webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(secret, body))
+
+	resp, err := w.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook callback returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
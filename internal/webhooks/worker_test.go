@@ -0,0 +1,134 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stellar/go/keypair"
+	"github.com/stellar/wallet-backend/internal/data"
+	"github.com/stellar/wallet-backend/internal/db"
+	"github.com/stellar/wallet-backend/internal/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerDeliversSignedPayload(t *testing.T) {
+	dbt := dbtest.Open(t)
+	defer dbt.Close()
+
+	dbConnectionPool, err := db.OpenDBConnectionPool(dbt.DSN)
+	require.NoError(t, err)
+	defer dbConnectionPool.Close()
+
+	models, err := data.NewModels(dbConnectionPool)
+	require.NoError(t, err)
+
+	address := keypair.MustRandom().Address()
+	ctx := context.Background()
+	_, err = dbConnectionPool.ExecContext(ctx, "INSERT INTO accounts (stellar_address) VALUES ($1)", address)
+	require.NoError(t, err)
+
+	const secret = "test-secret"
+
+	var receivedBody []byte
+	var receivedSignature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedBody, _ = io.ReadAll(r.Body)
+		receivedSignature = r.Header.Get(SignatureHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	_, err = models.SubscriptionWebhook.Insert(ctx, address, server.URL, secret)
+	require.NoError(t, err)
+
+	// NewWorker's transport refuses to dial loopback/private addresses
+	// (see TestWorkerRefusesDeliveryToHostnameResolvingToLoopback), which
+	// would also block httptest's own loopback server, so this test builds
+	// a Worker with a plain client to exercise delivery mechanics directly.
+	worker := &Worker{Models: models, HTTPClient: &http.Client{Timeout: 10 * time.Second}}
+	err = worker.HandlePaymentEvent(ctx, PaymentEvent{
+		Address:   address,
+		TxHash:    "deadbeef",
+		Amount:    "10.0000000",
+		Asset:     "native",
+		CreatedAt: "2026-07-27T00:00:00Z",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, worker.ProcessDue(ctx, DefaultBatchSize))
+
+	require.NotEmpty(t, receivedBody)
+	assert.Equal(t, Sign(secret, receivedBody), receivedSignature)
+
+	var envelope Envelope
+	require.NoError(t, json.Unmarshal(receivedBody, &envelope))
+	assert.Equal(t, address, envelope.Address)
+	assert.Equal(t, "deadbeef", envelope.TxHash)
+	assert.NotEmpty(t, envelope.ID)
+
+	deliveries, err := models.WebhookDelivery.ListFailed(ctx, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+}
+
+// TestWorkerRefusesDeliveryToHostnameResolvingToLoopback proves the SSRF
+// guard is enforced at delivery time against the *resolved* address, not
+// just against literal IPs at subscribe time. "localhost" passes
+// ValidateCallbackURL (it isn't a literal IP) but resolves to a loopback
+// address, so the worker's own client must still refuse to dial it.
+func TestWorkerRefusesDeliveryToHostnameResolvingToLoopback(t *testing.T) {
+	dbt := dbtest.Open(t)
+	defer dbt.Close()
+
+	dbConnectionPool, err := db.OpenDBConnectionPool(dbt.DSN)
+	require.NoError(t, err)
+	defer dbConnectionPool.Close()
+
+	models, err := data.NewModels(dbConnectionPool)
+	require.NoError(t, err)
+
+	address := keypair.MustRandom().Address()
+	ctx := context.Background()
+	_, err = dbConnectionPool.ExecContext(ctx, "INSERT INTO accounts (stellar_address) VALUES ($1)", address)
+	require.NoError(t, err)
+
+	var received bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	webhook, err := models.SubscriptionWebhook.Insert(ctx, address, "https://localhost:"+serverURL.Port()+"/hook", "test-secret")
+	require.NoError(t, err)
+
+	worker := NewWorker(models)
+	err = worker.HandlePaymentEvent(ctx, PaymentEvent{
+		Address:   address,
+		TxHash:    "deadbeef",
+		Amount:    "10.0000000",
+		Asset:     "native",
+		CreatedAt: "2026-07-27T00:00:00Z",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, worker.ProcessDue(ctx, DefaultBatchSize))
+
+	assert.False(t, received)
+
+	delivery, err := models.WebhookDelivery.InsertIfNotExists(ctx, webhook.ID, "deadbeef", []byte(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, data.WebhookDeliveryStatusFailed, delivery.Status)
+	require.True(t, delivery.LastError.Valid)
+	assert.Contains(t, delivery.LastError.String, "disallowed")
+}